@@ -0,0 +1,156 @@
+// Package feed marshals blog posts into Atom 1.0 and RSS 2.0 documents.
+package feed
+
+import (
+	"encoding/xml"
+	"fmt"
+	"time"
+)
+
+// mtimeLayout matches the format posts store their modification time in
+// (see router.getLatestCommitDate).
+const mtimeLayout = "2006-01-02 15:04:05"
+
+// Post is the minimal information needed to render a single feed entry.
+// It intentionally mirrors the fields of router.Post rather than importing
+// that package, to keep this package dependency-free.
+type Post struct {
+	Name  string
+	Title string
+	Body  string
+	Mtime string
+}
+
+// Config carries the blog-wide settings needed to build feed identifiers.
+type Config struct {
+	Domain    string
+	Author    string
+	StartDate string
+}
+
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	Title   string      `xml:"title"`
+	ID      string      `xml:"id"`
+	Updated string      `xml:"updated"`
+	Author  atomPerson  `xml:"author"`
+	Links   []atomLink  `xml:"link"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomPerson struct {
+	Name string `xml:"name"`
+}
+
+type atomLink struct {
+	Rel  string `xml:"rel,attr"`
+	Href string `xml:"href,attr"`
+}
+
+type atomEntry struct {
+	Title   string      `xml:"title"`
+	ID      string      `xml:"id"`
+	Updated string      `xml:"updated"`
+	Link    atomLink    `xml:"link"`
+	Content atomContent `xml:"content"`
+}
+
+type atomContent struct {
+	Type string `xml:"type,attr"`
+	Body string `xml:",cdata"`
+}
+
+// tagURI builds a stable tag URI per RFC 4151, e.g.
+// tag:example.com,2020-01-01:posts/hello-world
+func tagURI(cfg Config, name string) string {
+	return fmt.Sprintf("tag:%s,%s:posts/%s", cfg.Domain, cfg.StartDate, name)
+}
+
+func parseMtime(mtime string) time.Time {
+	t, err := time.Parse(mtimeLayout, mtime)
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
+
+// GenerateAtom renders posts as an Atom 1.0 feed.
+func GenerateAtom(cfg Config, title string, posts []Post) ([]byte, error) {
+	feed := atomFeed{
+		Title:  title,
+		ID:     tagURI(cfg, ""),
+		Author: atomPerson{Name: cfg.Author},
+		Links: []atomLink{
+			{Rel: "self", Href: "https://" + cfg.Domain + "/feed.atom"},
+		},
+	}
+
+	updated := time.Time{}
+	for _, post := range posts {
+		entryURL := "https://" + cfg.Domain + "/posts/" + post.Name
+		mtime := parseMtime(post.Mtime)
+		if mtime.After(updated) {
+			updated = mtime
+		}
+		feed.Entries = append(feed.Entries, atomEntry{
+			Title:   post.Title,
+			ID:      tagURI(cfg, post.Name),
+			Updated: mtime.Format(time.RFC3339),
+			Link:    atomLink{Rel: "alternate", Href: entryURL},
+			Content: atomContent{Type: "html", Body: post.Body},
+		})
+	}
+	feed.Updated = updated.Format(time.RFC3339)
+
+	out, err := xml.MarshalIndent(feed, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), out...), nil
+}
+
+type rssFeed struct {
+	XMLName xml.Name `xml:"rss"`
+	Version string   `xml:"version,attr"`
+	Channel rssChan  `xml:"channel"`
+}
+
+type rssChan struct {
+	Title string    `xml:"title"`
+	Link  string    `xml:"link"`
+	Items []rssItem `xml:"item"`
+}
+
+type rssItem struct {
+	Title       string `xml:"title"`
+	Link        string `xml:"link"`
+	GUID        string `xml:"guid"`
+	PubDate     string `xml:"pubDate"`
+	Description string `xml:"description"`
+}
+
+// GenerateRSS renders posts as an RSS 2.0 feed.
+func GenerateRSS(cfg Config, title string, posts []Post) ([]byte, error) {
+	channel := rssChan{
+		Title: title,
+		Link:  "https://" + cfg.Domain,
+	}
+
+	for _, post := range posts {
+		entryURL := "https://" + cfg.Domain + "/posts/" + post.Name
+		mtime := parseMtime(post.Mtime)
+		channel.Items = append(channel.Items, rssItem{
+			Title:       post.Title,
+			Link:        entryURL,
+			GUID:        tagURI(cfg, post.Name),
+			PubDate:     mtime.Format(time.RFC1123Z),
+			Description: post.Body,
+		})
+	}
+
+	out, err := xml.MarshalIndent(rssFeed{Version: "2.0", Channel: channel}, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), out...), nil
+}
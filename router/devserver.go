@@ -0,0 +1,183 @@
+package router
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// reloadBroker fans out live-reload notifications to every browser tab
+// connected to /_dev/reload.
+type reloadBroker struct {
+	mu      sync.Mutex
+	clients map[chan struct{}]struct{}
+}
+
+func newReloadBroker() *reloadBroker {
+	return &reloadBroker{clients: make(map[chan struct{}]struct{})}
+}
+
+func (b *reloadBroker) subscribe() chan struct{} {
+	ch := make(chan struct{}, 1)
+	b.mu.Lock()
+	b.clients[ch] = struct{}{}
+	b.mu.Unlock()
+	return ch
+}
+
+func (b *reloadBroker) unsubscribe(ch chan struct{}) {
+	b.mu.Lock()
+	delete(b.clients, ch)
+	b.mu.Unlock()
+	close(ch)
+}
+
+func (b *reloadBroker) broadcast() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for ch := range b.clients {
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	}
+}
+
+var devReload = newReloadBroker()
+
+// RunDevServer starts the blog server in live-reload mode: it watches
+// dataDir for changes, republishes the affected post as soon as its
+// directory is touched, and pushes a reload event over SSE so an open
+// browser tab refreshes itself. This gives post authors an edit-and-see
+// loop without needing a git push cycle.
+func RunDevServer() {
+	loadConfig()
+	config.DevMode = true
+
+	if err := checkAllPosts(); err != nil {
+		log.Println("initial post scan failed:", err)
+	}
+
+	r := newRouter()
+	r.Get("/_dev/reload", serveDevReload)
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		log.Fatalln(err)
+	}
+	defer watcher.Close()
+
+	if err := watchDataDir(watcher); err != nil {
+		log.Fatalln(err)
+	}
+	go watchPosts(watcher)
+
+	srv := &http.Server{Addr: config.WebIP + ":" + config.WebPort, Handler: r}
+
+	go func() {
+		log.Println("Starting dev server on " + srv.Addr)
+		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Fatalln(err)
+		}
+	}()
+
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+	<-stop
+
+	log.Println("Shutting down dev server...")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := srv.Shutdown(ctx); err != nil {
+		log.Println("dev server shutdown error:", err)
+	}
+}
+
+// watchDataDir adds a watch on dataDir and every post subdirectory, since
+// fsnotify does not watch recursively on its own.
+func watchDataDir(watcher *fsnotify.Watcher) error {
+	return filepath.Walk(dataDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return watcher.Add(path)
+		}
+		return nil
+	})
+}
+
+func watchPosts(watcher *fsnotify.Watcher) {
+	for {
+		select {
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return
+			}
+			if event.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Remove) == 0 {
+				continue
+			}
+			postName := postNameFromPath(event.Name)
+			if postName == "" {
+				continue
+			}
+			log.Println("dev: change detected in", postName)
+			updatePost(postName)
+			devReload.broadcast()
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+			log.Println("dev watcher error:", err)
+		}
+	}
+}
+
+// postNameFromPath maps a path fsnotify reports (e.g. dataDir/my-post/README.md)
+// back to the post directory name, skipping dataDir's own dotfiles.
+func postNameFromPath(path string) string {
+	rel, err := filepath.Rel(dataDir, path)
+	if err != nil {
+		return ""
+	}
+	parts := strings.Split(rel, string(os.PathSeparator))
+	if len(parts) == 0 || strings.HasPrefix(parts[0], ".") {
+		return ""
+	}
+	return parts[0]
+}
+
+func serveDevReload(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+
+	ch := devReload.subscribe()
+	defer devReload.unsubscribe(ch)
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-ch:
+			fmt.Fprint(w, "event: reload\ndata: reload\n\n")
+			flusher.Flush()
+		}
+	}
+}
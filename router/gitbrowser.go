@@ -0,0 +1,387 @@
+package router
+
+import (
+	"html/template"
+	"io"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/storer"
+	"github.com/sourcegraph/syntaxhighlight"
+)
+
+// logPageSize is the number of commits rendered per /{gitName}/log page.
+const logPageSize = 20
+
+// openRepo opens one of the bare repos gitkit manages under repoDir.
+func openRepo(gitName string) (*git.Repository, error) {
+	return git.PlainOpen(repoDir + gitName)
+}
+
+func resolveCommit(repo *git.Repository, ref string) (*object.Commit, error) {
+	hash, err := repo.ResolveRevision(plumbing.Revision(ref))
+	if err != nil {
+		return nil, err
+	}
+	return repo.CommitObject(*hash)
+}
+
+func renderGitTemplate(w http.ResponseWriter, name string, data interface{}) {
+	files, err := filepath.Glob(tmplPath)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	tmpl, err := template.ParseFiles(files...)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if err := tmpl.ExecuteTemplate(w, name, data); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+type logEntry struct {
+	Hash    string
+	Short   string
+	Message string
+	Author  string
+	When    string
+}
+
+// getLog renders a paginated commit history for a repo, walking back from
+// HEAD, ?page= pages of logPageSize commits at a time.
+func getLog(w http.ResponseWriter, r *http.Request) {
+	gitName := chi.URLParam(r, "gitName")
+	repo, err := openRepo(gitName)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	head, err := repo.Head()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	if page < 0 {
+		page = 0
+	}
+
+	commitIter, err := repo.Log(&git.LogOptions{From: head.Hash()})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer commitIter.Close()
+
+	var entries []logEntry
+	skip := page * logPageSize
+	seen := 0
+	err = commitIter.ForEach(func(c *object.Commit) error {
+		defer func() { seen++ }()
+		if seen < skip {
+			return nil
+		}
+		if len(entries) >= logPageSize {
+			return storer.ErrStop
+		}
+		entries = append(entries, logEntry{
+			Hash:    c.Hash.String(),
+			Short:   c.Hash.String()[:7],
+			Message: strings.TrimSpace(c.Message),
+			Author:  c.Author.Name,
+			When:    c.Author.When.Format("2006-01-02 15:04:05"),
+		})
+		return nil
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	renderGitTemplate(w, "log.tmpl.html", struct {
+		Title   string
+		Header  string
+		GitName string
+		Commits []logEntry
+		Page    int
+	}{
+		Title:   config.BlogTitle + " - " + gitName + " log",
+		Header:  config.BlogHeader,
+		GitName: gitName,
+		Commits: entries,
+		Page:    page,
+	})
+}
+
+// getCommitDiff renders a unified diff for a single commit against its
+// first parent (or against an empty tree for the root commit).
+func getCommitDiff(w http.ResponseWriter, r *http.Request) {
+	gitName := chi.URLParam(r, "gitName")
+	hash := chi.URLParam(r, "hash")
+
+	repo, err := openRepo(gitName)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	commit, err := repo.CommitObject(plumbing.NewHash(hash))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	tree, err := commit.Tree()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	var parentTree *object.Tree
+	if commit.NumParents() > 0 {
+		if parent, err := commit.Parent(0); err == nil {
+			parentTree, _ = parent.Tree()
+		}
+	}
+
+	changes, err := object.DiffTree(parentTree, tree)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	patch, err := changes.Patch()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	renderGitTemplate(w, "commit.tmpl.html", struct {
+		Title   string
+		Header  string
+		GitName string
+		Hash    string
+		Short   string
+		Message string
+		Diff    string
+	}{
+		Title:   config.BlogTitle + " - " + gitName + " commit " + hash[:7],
+		Header:  config.BlogHeader,
+		GitName: gitName,
+		Hash:    hash,
+		Short:   hash[:7],
+		Message: strings.TrimSpace(commit.Message),
+		Diff:    patch.String(),
+	})
+}
+
+type treeEntry struct {
+	Name  string
+	Path  string
+	IsDir bool
+}
+
+// getTree renders a directory listing for {gitName}/tree/{ref}/{path}, or
+// a syntax-highlighted blob view when the path resolves to a file.
+func getTree(w http.ResponseWriter, r *http.Request) {
+	gitName := chi.URLParam(r, "gitName")
+	ref := chi.URLParam(r, "ref")
+	path := strings.TrimPrefix(chi.URLParam(r, "*"), "/")
+
+	repo, err := openRepo(gitName)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	commit, err := resolveCommit(repo, ref)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	rootTree, err := commit.Tree()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if path != "" {
+		if file, ferr := rootTree.File(path); ferr == nil {
+			renderBlob(w, gitName, ref, path, file)
+			return
+		}
+	}
+
+	tree := rootTree
+	if path != "" {
+		subtree, terr := rootTree.Tree(path)
+		if terr != nil {
+			http.Error(w, terr.Error(), http.StatusNotFound)
+			return
+		}
+		tree = subtree
+	}
+
+	entries := make([]treeEntry, 0, len(tree.Entries))
+	for _, e := range tree.Entries {
+		entryPath := e.Name
+		if path != "" {
+			entryPath = path + "/" + e.Name
+		}
+		entries = append(entries, treeEntry{
+			Name:  e.Name,
+			Path:  entryPath,
+			IsDir: e.Mode == filemode.Dir,
+		})
+	}
+
+	renderGitTemplate(w, "tree.tmpl.html", struct {
+		Title   string
+		Header  string
+		GitName string
+		Ref     string
+		Path    string
+		Entries []treeEntry
+	}{
+		Title:   config.BlogTitle + " - " + gitName + " tree",
+		Header:  config.BlogHeader,
+		GitName: gitName,
+		Ref:     ref,
+		Path:    path,
+		Entries: entries,
+	})
+}
+
+func renderBlob(w http.ResponseWriter, gitName, ref, path string, file *object.File) {
+	contents, err := file.Contents()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	highlighted, err := syntaxhighlight.AsHTML([]byte(contents))
+	if err != nil {
+		highlighted = []byte(template.HTMLEscapeString(contents))
+	}
+
+	renderGitTemplate(w, "tree.tmpl.html", struct {
+		Title   string
+		Header  string
+		GitName string
+		Ref     string
+		Path    string
+		Blob    template.HTML
+	}{
+		Title:   config.BlogTitle + " - " + gitName + "/" + path,
+		Header:  config.BlogHeader,
+		GitName: gitName,
+		Ref:     ref,
+		Path:    path,
+		Blob:    template.HTML(highlighted),
+	})
+}
+
+type refEntry struct {
+	Name string
+	Hash string
+}
+
+// getRefs lists every branch and tag in a repo.
+func getRefs(w http.ResponseWriter, r *http.Request) {
+	gitName := chi.URLParam(r, "gitName")
+	repo, err := openRepo(gitName)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	var branches []refEntry
+	if branchIter, err := repo.Branches(); err == nil {
+		branchIter.ForEach(func(ref *plumbing.Reference) error {
+			branches = append(branches, refEntry{Name: ref.Name().Short(), Hash: ref.Hash().String()})
+			return nil
+		})
+	}
+
+	var tags []refEntry
+	if tagIter, err := repo.Tags(); err == nil {
+		tagIter.ForEach(func(ref *plumbing.Reference) error {
+			tags = append(tags, refEntry{Name: ref.Name().Short(), Hash: ref.Hash().String()})
+			return nil
+		})
+	}
+
+	renderGitTemplate(w, "refs.tmpl.html", struct {
+		Title    string
+		Header   string
+		GitName  string
+		Branches []refEntry
+		Tags     []refEntry
+	}{
+		Title:    config.BlogTitle + " - " + gitName + " refs",
+		Header:   config.BlogHeader,
+		GitName:  gitName,
+		Branches: branches,
+		Tags:     tags,
+	})
+}
+
+// getRaw streams a blob's raw bytes, e.g. for linking to images referenced
+// from a commit's diff or a post's markdown.
+func getRaw(w http.ResponseWriter, r *http.Request) {
+	gitName := chi.URLParam(r, "gitName")
+	ref := chi.URLParam(r, "ref")
+	path := strings.TrimPrefix(chi.URLParam(r, "*"), "/")
+
+	repo, err := openRepo(gitName)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	commit, err := resolveCommit(repo, ref)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	tree, err := commit.Tree()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	file, err := tree.File(path)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	reader, err := file.Reader()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer reader.Close()
+
+	if contentType := mime.TypeByExtension(filepath.Ext(path)); contentType != "" {
+		w.Header().Set("Content-Type", contentType)
+	}
+	io.Copy(w, reader)
+}
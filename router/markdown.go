@@ -0,0 +1,37 @@
+package router
+
+import (
+	"bytes"
+
+	"github.com/yuin/goldmark"
+	emoji "github.com/yuin/goldmark-emoji"
+	meta "github.com/yuin/goldmark-meta"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/parser"
+	"go.abhg.dev/goldmark/mermaid"
+)
+
+// markdown is the shared goldmark instance used to render every post and
+// page. It is configured once with the extensions the blog relies on:
+// GFM (tables, strikethrough, task lists, autolinks), emoji shortcodes,
+// Mermaid fenced-code diagrams, and YAML front-matter.
+var markdown = goldmark.New(
+	goldmark.WithExtensions(
+		extension.GFM,
+		emoji.Emoji,
+		&mermaid.Extender{},
+		meta.Meta,
+	),
+)
+
+// renderMarkdown converts markdown content to HTML and returns any YAML
+// front-matter parsed from the header alongside it. The returned map is
+// empty when the post has no front-matter block.
+func renderMarkdown(content []byte) ([]byte, map[string]interface{}, error) {
+	var buf bytes.Buffer
+	ctx := parser.NewContext()
+	if err := markdown.Convert(content, &buf, parser.WithContext(ctx)); err != nil {
+		return nil, nil, err
+	}
+	return buf.Bytes(), meta.Get(ctx), nil
+}
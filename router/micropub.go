@@ -0,0 +1,306 @@
+package router
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// handleMicropub implements a W3C Micropub endpoint at /micropub so
+// IndieWeb clients (Quill, Indigenous, ...) can publish posts without
+// pushing git commits. GET handles the `config` query, POST accepts both
+// `h=entry` form submissions and the JSON representation.
+func handleMicropub(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		handleMicropubQuery(w, r)
+	case http.MethodPost:
+		handleMicropubCreate(w, r)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func handleMicropubQuery(w http.ResponseWriter, r *http.Request) {
+	if !authorizeMicropub(r) {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	switch r.URL.Query().Get("q") {
+	case "config":
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"media-endpoint": "/micropub",
+			"syndicate-to":   config.SyndicateTo,
+		})
+	default:
+		http.Error(w, "unsupported query", http.StatusBadRequest)
+	}
+}
+
+// authorizeMicropub validates the bearer token against a static,
+// self-hosted token if one is configured, otherwise falls back to
+// checking it against the configured IndieAuth token endpoint.
+func authorizeMicropub(r *http.Request) bool {
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if token == "" {
+		token = r.FormValue("access_token")
+	}
+	if token == "" {
+		return false
+	}
+
+	if config.MicropubToken != "" {
+		return token == config.MicropubToken
+	}
+
+	if config.IndieAuthEndpoint != "" {
+		return verifyIndieAuthToken(token)
+	}
+
+	return false
+}
+
+func verifyIndieAuthToken(token string) bool {
+	req, err := http.NewRequest(http.MethodGet, config.IndieAuthEndpoint, nil)
+	if err != nil {
+		return false
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return false
+	}
+	defer resp.Body.Close()
+
+	return resp.StatusCode == http.StatusOK
+}
+
+// micropubEntry is the normalised form of an incoming h=entry post,
+// regardless of whether it arrived as multipart form data or JSON.
+type micropubEntry struct {
+	slug       string
+	title      string
+	content    string
+	tags       []string
+	photoPaths []string
+}
+
+func handleMicropubCreate(w http.ResponseWriter, r *http.Request) {
+	if !authorizeMicropub(r) {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	entry, err := parseMicropubEntry(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := createMicropubPost(entry); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Location", "/posts/"+entry.slug)
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func parseMicropubEntry(r *http.Request) (micropubEntry, error) {
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		return parseMicropubJSON(r)
+	}
+	return parseMicropubForm(r)
+}
+
+func parseMicropubJSON(r *http.Request) (micropubEntry, error) {
+	var body struct {
+		Properties map[string][]string `json:"properties"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		return micropubEntry{}, err
+	}
+
+	entry := micropubEntry{}
+	if v := body.Properties["content"]; len(v) > 0 {
+		entry.content = v[0]
+	}
+	if v := body.Properties["name"]; len(v) > 0 {
+		entry.title = v[0]
+	}
+	var rawSlug string
+	if v := body.Properties["mp-slug"]; len(v) > 0 {
+		rawSlug = v[0]
+	}
+	entry.tags = body.Properties["category"]
+	entry.slug = resolveSlug(rawSlug, entry.title, entry.content)
+	return entry, nil
+}
+
+func parseMicropubForm(r *http.Request) (micropubEntry, error) {
+	if err := r.ParseMultipartForm(32 << 20); err != nil && err != http.ErrNotMultipart {
+		return micropubEntry{}, err
+	}
+
+	entry := micropubEntry{
+		title:   r.FormValue("name"),
+		content: r.FormValue("content"),
+		tags:    r.Form["category[]"],
+	}
+	if len(entry.tags) == 0 {
+		entry.tags = r.Form["category"]
+	}
+	entry.slug = resolveSlug(r.FormValue("mp-slug"), entry.title, entry.content)
+
+	if r.MultipartForm != nil {
+		for _, fh := range r.MultipartForm.File["photo[]"] {
+			name, err := saveMicropubPhoto(entry.slug, fh)
+			if err != nil {
+				return entry, err
+			}
+			entry.photoPaths = append(entry.photoPaths, name)
+		}
+	}
+
+	return entry, nil
+}
+
+func saveMicropubPhoto(slug string, fh *multipart.FileHeader) (string, error) {
+	src, err := fh.Open()
+	if err != nil {
+		return "", err
+	}
+	defer src.Close()
+
+	postDir := filepath.Join(dataDir, slug)
+	if err := os.MkdirAll(postDir, 0755); err != nil {
+		return "", err
+	}
+
+	name := filepath.Base(fh.Filename)
+	dst, err := os.Create(filepath.Join(postDir, name))
+	if err != nil {
+		return "", err
+	}
+	defer dst.Close()
+
+	if _, err := io.Copy(dst, src); err != nil {
+		return "", err
+	}
+
+	return name, nil
+}
+
+// createMicropubPost writes the entry out as a README.md with a YAML
+// front-matter block, then republishes it through the normal post
+// pipeline the same way a git push does.
+func createMicropubPost(entry micropubEntry) error {
+	postDir := filepath.Join(dataDir, entry.slug)
+	if err := os.MkdirAll(postDir, 0755); err != nil {
+		return err
+	}
+
+	var readme strings.Builder
+	readme.WriteString("---\n")
+	fmt.Fprintf(&readme, "title: %q\n", entry.title)
+	readme.WriteString("date: " + time.Now().Format(time.RFC3339) + "\n")
+	if len(entry.tags) > 0 {
+		readme.WriteString("tags: [" + strings.Join(entry.tags, ", ") + "]\n")
+	}
+	readme.WriteString("visibility: public\n")
+	readme.WriteString("---\n\n")
+
+	if entry.title != "" {
+		fmt.Fprintf(&readme, "# %s\n\n", entry.title)
+	}
+	readme.WriteString(entry.content + "\n")
+	for _, photo := range entry.photoPaths {
+		fmt.Fprintf(&readme, "\n![](%s)\n", photo)
+	}
+
+	if err := os.WriteFile(filepath.Join(postDir, "README.md"), []byte(readme.String()), 0644); err != nil {
+		return err
+	}
+
+	updatePost(entry.slug)
+	return nil
+}
+
+// resolveSlug sanitizes a client-supplied mp-slug into a safe directory
+// name and disambiguates it if a post with that slug already exists on
+// disk, falling back to a derived slug when the client didn't send one.
+func resolveSlug(rawSlug, title, content string) string {
+	slug := sanitizeSlug(rawSlug)
+	if slug == "" {
+		return slugify(title, content)
+	}
+	if _, err := os.Stat(filepath.Join(dataDir, slug)); err == nil {
+		return fmt.Sprintf("%s-%d", slug, time.Now().Unix())
+	}
+	return slug
+}
+
+// sanitizeSlug restricts a client-supplied slug to the same URL-safe
+// character set slugify produces, so it can never escape dataDir via
+// "../" or an absolute path.
+func sanitizeSlug(raw string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(raw) {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+			b.WriteRune(r)
+		default:
+			b.WriteRune('-')
+		}
+	}
+	slug := strings.Trim(b.String(), "-")
+	for strings.Contains(slug, "--") {
+		slug = strings.ReplaceAll(slug, "--", "-")
+	}
+	return slug
+}
+
+// slugify derives a URL-safe directory name from the entry's title, or
+// the first few words of its content for title-less notes.
+func slugify(title, content string) string {
+	base := title
+	if base == "" {
+		words := strings.Fields(content)
+		if len(words) > 6 {
+			words = words[:6]
+		}
+		base = strings.Join(words, " ")
+	}
+	if base == "" {
+		base = "note"
+	}
+
+	var b strings.Builder
+	for _, r := range strings.ToLower(base) {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+			b.WriteRune(r)
+		default:
+			b.WriteRune('-')
+		}
+	}
+	slug := strings.Trim(b.String(), "-")
+	for strings.Contains(slug, "--") {
+		slug = strings.ReplaceAll(slug, "--", "-")
+	}
+	if slug == "" {
+		slug = "note"
+	}
+
+	return fmt.Sprintf("%s-%d", slug, time.Now().Unix())
+}
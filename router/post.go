@@ -1,62 +1,130 @@
 package router
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"html/template"
+	"io"
 	"log"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"runtime"
 	"sort"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
-	"github.com/russross/blackfriday/v2"
+	"golang.org/x/sync/errgroup"
 )
 
 const postListJson = dataDir + ".pages/postsList.json"
 
 type Post struct {
-	Name   string
-	Title  string
-	Body   template.HTML
-	Banner string
-	Mtime  string
-	State  string
+	Name        string
+	Title       string
+	Body        template.HTML
+	Banner      string
+	Mtime       string
+	State       string
+	Tags        []string
+	Date        string
+	Summary     string
+	Draft       bool
+	ContentHash string
 }
 
+// postsMu guards posts and publicPosts: checkAllPosts/updatePost write to
+// them from the scanner, git-receive-pack hook and dev-mode watcher, while
+// HTTP handlers read them concurrently.
+var postsMu sync.RWMutex
 var posts []Post
 var publicPosts []Post
 
+// fullRenderCount counts calls that fall through the incremental skip in
+// getPostInfoIncremental and pay for a full getPostInfo render. Tests use
+// it to assert a warm scan actually took the skip path.
+var fullRenderCount int64
+
+func snapshotPosts() []Post {
+	postsMu.RLock()
+	defer postsMu.RUnlock()
+	out := make([]Post, len(posts))
+	copy(out, posts)
+	return out
+}
+
+func snapshotPublicPosts() []Post {
+	postsMu.RLock()
+	defer postsMu.RUnlock()
+	out := make([]Post, len(publicPosts))
+	copy(out, publicPosts)
+	return out
+}
+
+// checkAllPosts rescans every post directory, parallelizing the expensive
+// getPostInfo render across runtime.NumCPU() workers. Posts whose
+// README.md hash and git HEAD haven't moved since the last scan are
+// reused as-is instead of re-rendered; see getPostInfoIncremental.
 func checkAllPosts() error {
 	files, err := os.ReadDir(dataDir)
 	if err != nil {
 		return err
 	}
 
-	for _, file := range files {
-		if file.IsDir() {
-			posts = updatePost(file.Name(), posts)
-		}
+	previous := make(map[string]Post)
+	for _, p := range snapshotPosts() {
+		previous[p.Name] = p
 	}
 
+	var mu sync.Mutex
+	var newPosts []Post
+
+	g := new(errgroup.Group)
+	g.SetLimit(runtime.NumCPU())
+
 	for _, file := range files {
-		post, err := getPostInfo(file.Name())
-		if err != nil {
-			posts = append(posts, post)
+		// dot directories such as .pages and .config hold site data, not posts
+		if !file.IsDir() || strings.HasPrefix(file.Name(), ".") {
+			continue
 		}
+		name := file.Name()
+		g.Go(func() error {
+			post, perr := getPostInfoIncremental(name, previous[name])
+			if perr != nil || post.State == "delete" {
+				os.RemoveAll(dataDir + name)
+				os.RemoveAll(repoDir + name)
+				return nil
+			}
+
+			mu.Lock()
+			newPosts = append(newPosts, post)
+			mu.Unlock()
+			return nil
+		})
 	}
 
-	posts = sortPosts(posts)
+	if err := g.Wait(); err != nil {
+		return err
+	}
 
-	publicPosts = []Post{}
-	for _, post := range posts {
+	newPosts = sortPosts(newPosts)
+
+	newPublic := make([]Post, 0, len(newPosts))
+	for _, post := range newPosts {
 		if post.State == "public" {
-			publicPosts = append(publicPosts, post)
+			newPublic = append(newPublic, post)
 		}
 	}
 
-	jsonData, err := json.MarshalIndent(posts, "", "  ")
+	postsMu.Lock()
+	posts = newPosts
+	publicPosts = newPublic
+	postsMu.Unlock()
+
+	jsonData, err := json.MarshalIndent(newPosts, "", "  ")
 	if err != nil {
 		return err
 	}
@@ -66,9 +134,44 @@ func checkAllPosts() error {
 		return err
 	}
 
+	regenerateFeeds()
+
 	return nil
 }
 
+// getPostInfoIncremental skips the markdown render and returns prev
+// unchanged when name's README.md content hash and git HEAD date match
+// what was recorded for it last scan.
+func getPostInfoIncremental(name string, prev Post) (Post, error) {
+	if _, err := os.Stat(dataDir + name); err != nil {
+		return Post{}, err
+	}
+
+	head := getLatestCommitDate(repoDir + name)
+	hash, hashErr := fileSHA256(dataDir + name + "/README.md")
+
+	if hashErr == nil && prev.Name == name && prev.ContentHash == hash && prev.Mtime == head {
+		return prev, nil
+	}
+
+	atomic.AddInt64(&fullRenderCount, 1)
+	return getPostInfo(name)
+}
+
+func fileSHA256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
 func sortPosts(posts []Post) []Post {
 	sort.Slice(posts, func(i, j int) bool {
 		return posts[i].Mtime > posts[j].Mtime
@@ -97,36 +200,105 @@ func getPostInfo(name string) (Post, error) {
 		return post, nil
 	}
 
-	// extract the first line
-	firstLine := strings.Split(string(mdContent), "\n")[0]
-	state := config.PostDefaultState
-	if strings.HasPrefix(firstLine, "<!--") {
-		if strings.Contains(firstLine, "public") {
-			state = "public"
-		} else if strings.Contains(firstLine, "private") {
-			state = "private"
-		} else if strings.Contains(firstLine, "delete") {
-			state = "delete"
+	contentHash := sha256.Sum256(mdContent)
+
+	htmlContent, frontMatter, err := renderMarkdown(mdContent)
+	if err != nil {
+		return post, err
+	}
+
+	// front-matter parsing is local-only, but the displayed HTML should
+	// still go through whichever renderer (local or Gitea) is configured
+	if _, local := activeRenderer.(localRenderer); !local {
+		if remoteHTML, rerr := cachedRender(activeRenderer, mdContent); rerr == nil {
+			htmlContent = remoteHTML
+		} else {
+			log.Println("unable to render via configured renderer, falling back to local html:", rerr)
+		}
+	}
+
+	state, tags, date, summary, draft, banner := extractFrontMatter(frontMatter, config.PostDefaultState)
+	if len(frontMatter) == 0 {
+		// no YAML front-matter: fall back to the legacy HTML-comment
+		// state marker on the first line
+		firstLine := strings.Split(string(mdContent), "\n")[0]
+		if strings.HasPrefix(firstLine, "<!--") {
+			if strings.Contains(firstLine, "public") {
+				state = "public"
+			} else if strings.Contains(firstLine, "private") {
+				state = "private"
+			} else if strings.Contains(firstLine, "delete") {
+				state = "delete"
+			}
 		}
 	}
 
-	htmlContent := blackfriday.Run(mdContent)
-	title, body, banner := extractTitleAndBody(htmlContent)
+	title, body, firstImage := extractTitleAndBody(htmlContent)
+	if banner == "" {
+		banner = firstImage
+	}
 	post = Post{
-		Name:   name,
-		Title:  title,
-		Body:   body,
-		Banner: dataDir + name + "/" + banner,
-		Mtime:  getLatestCommitDate(repoDir + name),
-		State:  state,
+		Name:        name,
+		Title:       title,
+		Body:        body,
+		Banner:      dataDir + name + "/" + banner,
+		Mtime:       getLatestCommitDate(repoDir + name),
+		State:       state,
+		Tags:        tags,
+		Date:        date,
+		Summary:     summary,
+		Draft:       draft,
+		ContentHash: hex.EncodeToString(contentHash[:]),
 	}
 	return post, nil
 }
 
+// extractFrontMatter derives a post's state, tags, date, summary, draft
+// flag, and banner from its parsed YAML front-matter. State is computed
+// from a `visibility: public|private|delete` key, overridden to "private"
+// whenever `draft: true` is set. An empty frontMatter yields the caller's
+// defaultState so the legacy HTML-comment parser can take over.
+func extractFrontMatter(frontMatter map[string]interface{}, defaultState string) (state string, tags []string, date string, summary string, draft bool, banner string) {
+	state = defaultState
+
+	if v, ok := frontMatter["visibility"].(string); ok && v != "" {
+		state = v
+	}
+	if v, ok := frontMatter["draft"].(bool); ok {
+		draft = v
+		if draft {
+			state = "private"
+		}
+	}
+	if v, ok := frontMatter["date"].(string); ok {
+		date = v
+	}
+	if v, ok := frontMatter["summary"].(string); ok {
+		summary = v
+	}
+	if v, ok := frontMatter["banner"].(string); ok {
+		banner = v
+	}
+	if rawTags, ok := frontMatter["tags"].([]interface{}); ok {
+		for _, t := range rawTags {
+			if s, ok := t.(string); ok {
+				tags = append(tags, s)
+			}
+		}
+	}
+
+	return
+}
+
 // Mtime:  getFileModifiedTime(dataDir + name).Format("2006-01-02 15:04:05"),
 
-func updatePost(name string, posts []Post) []Post {
+// updatePost re-renders a single post directory and merges it into the
+// shared post list, publishing it immediately after a git push or a
+// dev-mode filesystem change.
+func updatePost(name string) []Post {
 	post, err := getPostInfo(name)
+
+	postsMu.Lock()
 	if err != nil || post.State == "delete" {
 		posts = deletePost(name, posts)
 	} else {
@@ -145,17 +317,23 @@ func updatePost(name string, posts []Post) []Post {
 
 	posts = sortPosts(posts)
 
-	publicPosts = []Post{}
-	for _, post := range posts {
-		if post.State == "public" {
-			publicPosts = append(publicPosts, post)
+	publicPosts = make([]Post, 0, len(posts))
+	for _, p := range posts {
+		if p.State == "public" {
+			publicPosts = append(publicPosts, p)
 		}
 	}
 
-	jsonData, _ := json.MarshalIndent(posts, "", "  ")
+	snapshot := make([]Post, len(posts))
+	copy(snapshot, posts)
+	postsMu.Unlock()
+
+	jsonData, _ := json.MarshalIndent(snapshot, "", "  ")
 	os.WriteFile(postListJson, jsonData, 0644)
 
-	return posts
+	regenerateFeeds()
+
+	return snapshot
 }
 
 func deletePost(name string, posts []Post) []Post {
@@ -170,7 +348,10 @@ func deletePost(name string, posts []Post) []Post {
 	return posts
 }
 
-func getLatestCommitDate(repoDir string) string {
+// getLatestCommitDate is a var rather than a plain func so tests can stub
+// it out and get a deterministic head value instead of depending on real
+// git repos under repoDir.
+var getLatestCommitDate = func(repoDir string) string {
 	cmd := exec.Command("git", "-C", repoDir, "log", "-1", "--format=%cd", "--date=format:%Y-%m-%d %H:%M:%S")
 	output, err := cmd.Output()
 	if err != nil {
@@ -298,20 +479,20 @@ func GetPostsFromJson() ([]Post, []Post) {
 		return []Post{}, []Post{}
 	}
 	// convert the json to []Post
-	var posts []Post
-	err = json.Unmarshal(postList, &posts)
+	var loaded []Post
+	err = json.Unmarshal(postList, &loaded)
 	if err != nil {
 		return []Post{}, []Post{}
 	}
 
-	publicPosts = []Post{}
-	for _, post := range posts {
+	loadedPublic := make([]Post, 0, len(loaded))
+	for _, post := range loaded {
 		if post.State == "public" {
-			publicPosts = append(publicPosts, post)
+			loadedPublic = append(loadedPublic, post)
 		}
 	}
 
-	return posts, publicPosts
+	return loaded, loadedPublic
 }
 
 func AnaylzePosts() {
@@ -320,7 +501,12 @@ func AnaylzePosts() {
 		checkAllPosts()
 		log.Println("All posts checked")
 	} else {
-		posts, publicPosts = GetPostsFromJson()
+		loaded, loadedPublic := GetPostsFromJson()
+		postsMu.Lock()
+		posts = loaded
+		publicPosts = loadedPublic
+		postsMu.Unlock()
+		regenerateFeeds()
 		log.Println("Skip anaylzing posts")
 	}
 }
\ No newline at end of file
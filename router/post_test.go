@@ -0,0 +1,91 @@
+package router
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestCheckAllPostsConcurrentScan runs checkAllPosts against 1000 synthetic
+// posts to exercise the concurrent, incremental scan at scale. getLatestCommitDate
+// is stubbed to a fixed value so the warm pass's skip condition
+// (prev.Mtime == head) is deterministic rather than relying on two
+// real-time clock reads landing in the same second. The warm
+// (unchanged-content) pass should be substantially faster than the cold
+// pass since it skips the goldmark render entirely; run with `go test
+// -run TestCheckAllPostsConcurrentScan -v` (or `-bench` once a matching
+// Benchmark is added) to see the timing on your own hardware, as it
+// depends heavily on CPU count and disk speed.
+func TestCheckAllPostsConcurrentScan(t *testing.T) {
+	oldGetLatestCommitDate := getLatestCommitDate
+	getLatestCommitDate = func(repoDir string) string { return "2024-01-01 00:00:00" }
+	defer func() { getLatestCommitDate = oldGetLatestCommitDate }()
+
+	dir := t.TempDir()
+	oldWd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(oldWd)
+
+	if err := os.MkdirAll(dataDir+".pages", 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(repoDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	const numPosts = 1000
+	for i := 0; i < numPosts; i++ {
+		postDir := filepath.Join(dataDir, fmt.Sprintf("post-%d", i))
+		if err := os.MkdirAll(postDir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		content := fmt.Sprintf("---\ntitle: \"Post %d\"\nvisibility: public\n---\n\n# Post %d\n\nHello world.\n", i, i)
+		if err := os.WriteFile(filepath.Join(postDir, "README.md"), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	postsMu.Lock()
+	posts, publicPosts = nil, nil
+	postsMu.Unlock()
+
+	atomic.StoreInt64(&fullRenderCount, 0)
+
+	cold := time.Now()
+	if err := checkAllPosts(); err != nil {
+		t.Fatalf("cold checkAllPosts failed: %v", err)
+	}
+	coldElapsed := time.Since(cold)
+
+	if got := len(snapshotPosts()); got != numPosts {
+		t.Fatalf("expected %d posts, got %d", numPosts, got)
+	}
+	if got := len(snapshotPublicPosts()); got != numPosts {
+		t.Fatalf("expected %d public posts, got %d", numPosts, got)
+	}
+	if got := atomic.LoadInt64(&fullRenderCount); got != numPosts {
+		t.Fatalf("expected %d full renders on cold scan, got %d", numPosts, got)
+	}
+
+	atomic.StoreInt64(&fullRenderCount, 0)
+
+	warm := time.Now()
+	if err := checkAllPosts(); err != nil {
+		t.Fatalf("warm checkAllPosts failed: %v", err)
+	}
+	warmElapsed := time.Since(warm)
+
+	if got := atomic.LoadInt64(&fullRenderCount); got != 0 {
+		t.Fatalf("expected warm scan to take the incremental skip path for every post, but %d fell through to a full render", got)
+	}
+
+	t.Logf("cold scan: %s, warm (incremental) scan: %s", coldElapsed, warmElapsed)
+}
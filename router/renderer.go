@@ -0,0 +1,170 @@
+package router
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// giteaFailureThreshold is the number of consecutive remote failures that
+// trips the circuit breaker.
+const giteaFailureThreshold = 5
+
+// defaultFailureCooldown is used when config.FailureCooldown is unset.
+const defaultFailureCooldown = 60 * time.Second
+
+// Renderer converts markdown content to HTML. Implementations may reach
+// out to a remote service and are allowed to fail; callers decide how to
+// degrade. cacheable reports whether the result is safe to memoize: a
+// renderer that silently degraded to a local fallback (e.g. while its
+// circuit breaker is open) should report false so callers retry it
+// instead of caching the degraded output forever.
+type Renderer interface {
+	Render(content []byte) (html []byte, cacheable bool, err error)
+}
+
+// localRenderer renders through the in-process goldmark pipeline.
+type localRenderer struct{}
+
+func (localRenderer) Render(content []byte) ([]byte, bool, error) {
+	html, _, err := renderMarkdown(content)
+	return html, err == nil, err
+}
+
+// GiteaRemoteRenderer posts markdown to a Gitea instance's
+// /api/v1/markdown endpoint so posts get Gitea's emoji, @mention,
+// checkbox and issue-link rendering. After giteaFailureThreshold
+// consecutive failures it stops calling out for FailureCooldown and
+// falls through to Fallback instead, logging the degradation.
+type GiteaRemoteRenderer struct {
+	URL             string
+	Token           string
+	FailureCooldown time.Duration
+	Fallback        Renderer
+
+	mu              sync.Mutex
+	consecutiveFail int
+	cooldownUntil   time.Time
+}
+
+func (g *GiteaRemoteRenderer) Render(content []byte) ([]byte, bool, error) {
+	if g.inCooldown() {
+		html, _, err := g.Fallback.Render(content)
+		return html, false, err
+	}
+
+	html, err := g.renderRemote(content)
+	if err != nil {
+		g.recordFailure()
+		log.Println("gitea markdown render failed, falling back to local renderer:", err)
+		fallbackHTML, _, ferr := g.Fallback.Render(content)
+		return fallbackHTML, false, ferr
+	}
+
+	g.recordSuccess()
+	return html, true, nil
+}
+
+func (g *GiteaRemoteRenderer) inCooldown() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return time.Now().Before(g.cooldownUntil)
+}
+
+func (g *GiteaRemoteRenderer) recordFailure() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.consecutiveFail++
+	if g.consecutiveFail >= giteaFailureThreshold {
+		cooldown := g.FailureCooldown
+		if cooldown <= 0 {
+			cooldown = defaultFailureCooldown
+		}
+		g.cooldownUntil = time.Now().Add(cooldown)
+		log.Printf("gitea renderer tripped circuit breaker, cooling down for %s", cooldown)
+	}
+}
+
+func (g *GiteaRemoteRenderer) recordSuccess() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.consecutiveFail = 0
+}
+
+func (g *GiteaRemoteRenderer) renderRemote(content []byte) ([]byte, error) {
+	body, err := json.Marshal(map[string]string{
+		"Mode": "gfm",
+		"Text": string(content),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, strings.TrimRight(g.URL, "/")+"/api/v1/markdown", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if g.Token != "" {
+		req.Header.Set("Authorization", "token "+g.Token)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("gitea markdown api returned %s", resp.Status)
+	}
+
+	return io.ReadAll(resp.Body)
+}
+
+// activeRenderer is selected once from Config by initRenderer.
+var activeRenderer Renderer = localRenderer{}
+
+// renderCache memoizes rendered HTML by sha256(content) so repeated
+// getPostInfo/toHTML calls for an unchanged post don't re-hit the API.
+var renderCache sync.Map
+
+func initRenderer() {
+	if config.RendererMode == "gitea" && config.GiteaURL != "" {
+		activeRenderer = &GiteaRemoteRenderer{
+			URL:             config.GiteaURL,
+			Token:           config.GiteaToken,
+			FailureCooldown: time.Duration(config.FailureCooldown) * time.Second,
+			Fallback:        localRenderer{},
+		}
+	} else {
+		activeRenderer = localRenderer{}
+	}
+}
+
+func cachedRender(renderer Renderer, content []byte) ([]byte, error) {
+	sum := sha256.Sum256(content)
+	key := hex.EncodeToString(sum[:])
+
+	if cached, ok := renderCache.Load(key); ok {
+		return cached.([]byte), nil
+	}
+
+	html, cacheable, err := renderer.Render(content)
+	if err != nil {
+		return nil, err
+	}
+
+	if cacheable {
+		renderCache.Store(key, html)
+	}
+	return html, nil
+}
@@ -9,11 +9,12 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 
 	"github.com/PuerkitoBio/goquery"
 	"github.com/go-chi/chi"
 	"github.com/go-chi/chi/middleware"
-	"github.com/russross/blackfriday/v2"
+	"github.com/lwd-temp/git-blog/feed"
 	"github.com/sosedoff/gitkit"
 	"github.com/sourcegraph/syntaxhighlight"
 )
@@ -34,6 +35,13 @@ var faviconFiles = map[string]string{
 
 var config Config
 
+// feedMu guards atomFeedCache/rssFeedCache: regenerateFeeds writes them
+// from the same scanner/hook/watcher paths that mutate posts, while
+// getAtomFeed/getRSSFeed read them concurrently.
+var feedMu sync.RWMutex
+var atomFeedCache []byte
+var rssFeedCache []byte
+
 type Config struct {
 	AnaylzePostsOnStart bool
 	BlogHeader          string
@@ -43,6 +51,17 @@ type Config struct {
 	GitUserName         string
 	WebPort             string
 	WebIP               string
+	FeedAuthor          string
+	FeedDomain          string
+	FeedStartDate       string
+	DevMode             bool
+	MicropubToken       string
+	IndieAuthEndpoint   string
+	SyndicateTo         []string
+	RendererMode        string
+	GiteaURL            string
+	GiteaToken          string
+	FailureCooldown     int
 }
 
 type MyGitServer struct {
@@ -50,10 +69,14 @@ type MyGitServer struct {
 	additionalHander func(w http.ResponseWriter, r *http.Request)
 }
 
-func RunBlogServer() {
+func loadConfig() {
 	configJson, _ := os.ReadFile(dataDir + ".config/config.json")
 	json.Unmarshal(configJson, &config)
+	initRenderer()
+}
 
+// newRouter builds the chi router shared by RunBlogServer and RunDevServer.
+func newRouter() *chi.Mux {
 	r := chi.NewRouter()
 	r.Use(middleware.Logger)
 	r.Use(middleware.Recoverer)
@@ -66,6 +89,19 @@ func RunBlogServer() {
 		r.Get("/{pageName}", getPage)
 		r.Get("/posts/{postName}", getPost)
 		r.Get("/posts/{postName}/*", servePostAssets)
+		r.Get("/feed.atom", getAtomFeed)
+		r.Get("/feed.xml", getRSSFeed)
+		r.Get("/micropub", handleMicropub)
+		r.Post("/micropub", handleMicropub)
+		// repository browser - readers have no git credentials, so this is
+		// gated on post visibility instead: only a {gitName} that matches a
+		// public post's repo can be browsed, keeping private/draft history
+		// out of reach without locking the feature to the blog owner.
+		r.Get("/{gitName}/log", requirePublicRepo(getLog))
+		r.Get("/{gitName}/commit/{hash}", requirePublicRepo(getCommitDiff))
+		r.Get("/{gitName}/tree/{ref}/*", requirePublicRepo(getTree))
+		r.Get("/{gitName}/refs", requirePublicRepo(getRefs))
+		r.Get("/{gitName}/raw/{ref}/*", requirePublicRepo(getRaw))
 		r.Handle("/static/*", http.StripPrefix("/static/", http.FileServer(http.Dir(dataDir+".config/static/"))))
 		// git sevice
 		r.Handle("/{gitName}/info/*", gitServer)
@@ -79,6 +115,13 @@ func RunBlogServer() {
 		}
 	})
 
+	return r
+}
+
+func RunBlogServer() {
+	loadConfig()
+	r := newRouter()
+
 	log.Println("Starting server on " + config.WebIP + ":" + config.WebPort)
 	err := http.ListenAndServe(config.WebIP+":"+config.WebPort, r)
 	if err != nil {
@@ -114,6 +157,31 @@ func createGitServer() *MyGitServer {
 	}
 }
 
+// requirePublicRepo wraps a repository browser handler so it only ever
+// serves a {gitName} whose matching post is public, keeping private/draft
+// post history and raw blobs out of reach of anonymous readers without
+// requiring git push/pull credentials for what is a reader-facing feature.
+func requirePublicRepo(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		gitName := chi.URLParam(r, "gitName")
+		if !isPublicPost(gitName) {
+			http.Error(w, "Post not found", http.StatusNotFound)
+			return
+		}
+		next(w, r)
+	}
+}
+
+// isPublicPost reports whether name is a currently public post.
+func isPublicPost(name string) bool {
+	for _, p := range snapshotPublicPosts() {
+		if p.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
 func (s *MyGitServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	s.originalServer.ServeHTTP(w, r)
 	s.additionalHander(w, r)
@@ -124,7 +192,7 @@ func gitUpdate(w http.ResponseWriter, r *http.Request) {
 		gitName := chi.URLParam(r, "gitName")
 		log.Printf("git-receive-pack: %s", gitName)
 		extractGitData(gitName)
-		updatePost(gitName, posts)
+		updatePost(gitName)
 	}
 }
 
@@ -138,7 +206,7 @@ func getIndex(w http.ResponseWriter, r *http.Request) {
 
 	htmlContent := toHTML(content)
 
-	recentPosts := publicPosts
+	recentPosts := snapshotPublicPosts()
 	if len(recentPosts) > 5 {
 		recentPosts = recentPosts[:5]
 	}
@@ -160,11 +228,13 @@ func getIndex(w http.ResponseWriter, r *http.Request) {
 		Header       string
 		MarkdownHTML template.HTML
 		Posts        []Post
+		DevMode      bool
 	}{
 		Title:        config.BlogTitle,
 		Header:       config.BlogHeader,
 		MarkdownHTML: template.HTML(htmlContent),
 		Posts:        recentPosts,
+		DevMode:      config.DevMode,
 	}
 
 	err = tmpl.ExecuteTemplate(w, "index.tmpl.html", data)
@@ -192,10 +262,12 @@ func getPage(w http.ResponseWriter, r *http.Request) {
 		Title        string
 		Header       string
 		MarkdownHTML template.HTML
+		DevMode      bool
 	}{
 		Title:        config.BlogTitle + " - " + pageName,
 		Header:       config.BlogHeader,
 		MarkdownHTML: template.HTML(htmlContent),
+		DevMode:      config.DevMode,
 	}
 
 	files, err := filepath.Glob(tmplPath)
@@ -231,13 +303,15 @@ func getPosts(w http.ResponseWriter, r *http.Request) {
 	}
 
 	data := struct {
-		Title  string
-		Header string
-		Posts  []Post
+		Title   string
+		Header  string
+		Posts   []Post
+		DevMode bool
 	}{
-		Title:  config.BlogTitle + " - Posts",
-		Header: config.BlogHeader,
-		Posts:  publicPosts,
+		Title:   config.BlogTitle + " - Posts",
+		Header:  config.BlogHeader,
+		Posts:   snapshotPublicPosts(),
+		DevMode: config.DevMode,
 	}
 
 	err = tmpl.ExecuteTemplate(w, "posts.tmpl.html", data)
@@ -283,7 +357,7 @@ func getPost(w http.ResponseWriter, r *http.Request) {
 
 	// find the post in the list of posts
 	var post Post
-	for _, p := range posts {
+	for _, p := range snapshotPosts() {
 		if p.Name == postName {
 			post = p
 			break
@@ -294,10 +368,12 @@ func getPost(w http.ResponseWriter, r *http.Request) {
 		Title        string
 		Header       string
 		MarkdownHTML template.HTML
+		DevMode      bool
 	}{
 		Title:        config.BlogHeader + " - " + post.Title,
 		Header:       config.BlogHeader,
 		MarkdownHTML: template.HTML(htmlContent),
+		DevMode:      config.DevMode,
 	}
 
 	err = tmpl.ExecuteTemplate(w, "post.tmpl.html", data)
@@ -312,8 +388,69 @@ func servePostAssets(w http.ResponseWriter, r *http.Request) {
 	http.StripPrefix("/posts/"+postName+"/", http.FileServer(http.Dir(dataDir+postName+"/"))).ServeHTTP(w, r)
 }
 
+func getAtomFeed(w http.ResponseWriter, r *http.Request) {
+	feedMu.RLock()
+	cache := atomFeedCache
+	feedMu.RUnlock()
+	w.Header().Set("Content-Type", "application/atom+xml; charset=utf-8")
+	w.Write(cache)
+}
+
+func getRSSFeed(w http.ResponseWriter, r *http.Request) {
+	feedMu.RLock()
+	cache := rssFeedCache
+	feedMu.RUnlock()
+	w.Header().Set("Content-Type", "application/rss+xml; charset=utf-8")
+	w.Write(cache)
+}
+
+// regenerateFeeds rebuilds the cached Atom/RSS documents from publicPosts.
+// It is called after any change to the post list so subscribers see new
+// posts as soon as they are pushed.
+func regenerateFeeds() {
+	cfg := feed.Config{
+		Domain:    config.FeedDomain,
+		Author:    config.FeedAuthor,
+		StartDate: config.FeedStartDate,
+	}
+
+	currentPublic := snapshotPublicPosts()
+	feedPosts := make([]feed.Post, 0, len(currentPublic))
+	for _, post := range currentPublic {
+		feedPosts = append(feedPosts, feed.Post{
+			Name:  post.Name,
+			Title: post.Title,
+			Body:  string(post.Body),
+			Mtime: post.Mtime,
+		})
+	}
+
+	atom, atomErr := feed.GenerateAtom(cfg, config.BlogTitle, feedPosts)
+	if atomErr != nil {
+		log.Println("unable to generate atom feed:", atomErr)
+	}
+
+	rss, rssErr := feed.GenerateRSS(cfg, config.BlogTitle, feedPosts)
+	if rssErr != nil {
+		log.Println("unable to generate rss feed:", rssErr)
+	}
+
+	feedMu.Lock()
+	if atomErr == nil {
+		atomFeedCache = atom
+	}
+	if rssErr == nil {
+		rssFeedCache = rss
+	}
+	feedMu.Unlock()
+}
+
 func toHTML(content []byte) []byte {
-	htmlContent := blackfriday.Run(content)
+	htmlContent, err := cachedRender(activeRenderer, content)
+	if err != nil {
+		log.Println("unable to render markdown:", err)
+		htmlContent = content
+	}
 	htmlContent = []byte(replaceImagePaths(string(htmlContent), ""))
 	replaced, err := replaceCodeParts(htmlContent)
 	if err != nil {